@@ -0,0 +1,9 @@
+package container
+
+// ID is a container ID, as reported by the Docker/Compose CLI.
+type ID string
+
+func (id ID) String() string { return string(id) }
+
+// Empty returns true if no container ID was found.
+func (id ID) Empty() bool { return id == "" }
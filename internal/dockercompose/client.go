@@ -0,0 +1,467 @@
+package dockercompose
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/loader"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/tilt-dev/tilt/internal/container"
+	"github.com/tilt-dev/tilt/pkg/model"
+)
+
+// DockerComposeClient talks to `docker compose` (or an OCI-hosted
+// equivalent) on behalf of Tilt's compose controllers.
+type DockerComposeClient interface {
+	Up(ctx context.Context, spec model.DockerComposeUpSpec, shouldBuild bool, stdout, stderr io.Writer) error
+	Down(ctx context.Context, proj model.DockerComposeProject, stdout, stderr io.Writer) error
+	Rm(ctx context.Context, specs []model.DockerComposeUpSpec, stdout, stderr io.Writer) error
+	StreamLogs(ctx context.Context, spec model.DockerComposeUpSpec) io.ReadCloser
+	StreamEvents(ctx context.Context, p model.DockerComposeProject) (<-chan string, error)
+	// Watch drives x-develop.watch rules (sync, rebuild, sync+restart) via
+	// `docker compose watch`, streaming back the parsed event sequence.
+	Watch(ctx context.Context, proj model.DockerComposeProject, specs []model.DockerComposeUpSpec, stdout, stderr io.Writer) (<-chan string, error)
+	// Config renders the effective compose YAML for proj, reflecting its
+	// active profile set.
+	Config(ctx context.Context, proj model.DockerComposeProject) (string, error)
+	Project(ctx context.Context, m model.DockerComposeProject) (*types.Project, error)
+	ContainerID(ctx context.Context, spec model.DockerComposeUpSpec) (container.ID, error)
+	// Version returns the compose CLI's version, its variant ("docker-
+	// compose" or "tilt-fake"), and the feature capabilities derived from
+	// that version, so callers can gate features without parsing semver
+	// themselves.
+	Version(ctx context.Context) (string, string, ComposeCapabilities, error)
+}
+
+// DCClient is the real DockerComposeClient, backed by the `docker compose`
+// CLI.
+type DCClient struct {
+	// ociCacheDir is where OCI-hosted compose projects are materialized
+	// after being pulled from their registry.
+	ociCacheDir string
+}
+
+func NewDockerComposeClient() *DCClient {
+	return &DCClient{
+		ociCacheDir: filepath.Join(os.TempDir(), "tilt-dockercompose-oci"),
+	}
+}
+
+var _ DockerComposeClient = &DCClient{}
+
+func (c *DCClient) Up(ctx context.Context, spec model.DockerComposeUpSpec,
+	shouldBuild bool, stdout, stderr io.Writer) error {
+	args := []string{"compose", "up", "--detach", "--no-deps"}
+	if shouldBuild {
+		args = append(args, "--build", "--progress", buildxProgressMode(spec.BuildProgress))
+	}
+	args = append(args, spec.Service)
+
+	if shouldBuild && spec.BuildProgress == "rawjson" {
+		return c.runWithJSONBuildEvents(ctx, args, stdout, stderr)
+	}
+	return c.run(ctx, args, stdout, stderr)
+}
+
+// buildxProgressMode maps a model.DockerComposeUpSpec.BuildProgress value
+// to the `--progress` mode buildx understands. buildx has no native
+// "rawjson" mode, so Tilt requests plain output under the hood and
+// translates it into structured events itself (see
+// runWithJSONBuildEvents).
+func buildxProgressMode(mode string) string {
+	switch mode {
+	case "plain", "tty", "quiet":
+		return mode
+	case "rawjson":
+		return "plain"
+	default:
+		return "auto"
+	}
+}
+
+// buildProgressEvent is a single structured BuildKit progress update,
+// suitable for Tilt's UI to render as a per-step status instead of a raw
+// log line.
+type buildProgressEvent struct {
+	Step string `json:"step"`
+}
+
+// runWithJSONBuildEvents runs a `docker compose up --build` invocation and
+// rewrites its plain-progress stdout into one JSON buildProgressEvent per
+// line, so callers that asked for BuildProgress: "rawjson" get structured
+// events instead of raw BuildKit log lines.
+func (c *DCClient) runWithJSONBuildEvents(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = stderr
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(pipe)
+	enc := json.NewEncoder(stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := enc.Encode(buildProgressEvent{Step: line}); err != nil {
+			return err
+		}
+	}
+
+	return cmd.Wait()
+}
+
+func (c *DCClient) Down(ctx context.Context, proj model.DockerComposeProject, stdout, stderr io.Writer) error {
+	configPaths, err := c.resolveConfigPaths(ctx, proj)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"compose"}, configFileArgs(configPaths)...)
+	return c.run(ctx, append(args, "down"), stdout, stderr)
+}
+
+func (c *DCClient) Rm(ctx context.Context, specs []model.DockerComposeUpSpec, stdout, stderr io.Writer) error {
+	args := []string{"compose", "rm", "--force", "--stop"}
+	for _, spec := range specs {
+		args = append(args, spec.Service)
+	}
+	return c.run(ctx, args, stdout, stderr)
+}
+
+func (c *DCClient) StreamLogs(ctx context.Context, spec model.DockerComposeUpSpec) io.ReadCloser {
+	cmd := exec.CommandContext(ctx, "docker", "compose", "logs", "--follow", "--no-color", spec.Service)
+	reader, _ := cmd.StdoutPipe()
+	_ = cmd.Start()
+	return reader
+}
+
+func (c *DCClient) StreamEvents(ctx context.Context, p model.DockerComposeProject) (<-chan string, error) {
+	return nil, fmt.Errorf("StreamEvents not implemented for real DockerComposeClient")
+}
+
+// Watch invokes `docker compose watch` for the given specs and parses its
+// output into a stream of JSON-encoded WatchEvents.
+func (c *DCClient) Watch(ctx context.Context, proj model.DockerComposeProject,
+	specs []model.DockerComposeUpSpec, stdout, stderr io.Writer) (<-chan string, error) {
+	configPaths, err := c.resolveConfigPaths(ctx, proj)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"compose"}, configFileArgs(configPaths)...)
+	args = append(args, "watch")
+	for _, spec := range specs {
+		args = append(args, spec.Service)
+	}
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stderr = stderr
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	events := make(chan string, 10)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(pipe)
+		for scanner.Scan() {
+			evt, ok := parseWatchLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			j, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- string(j):
+			case <-ctx.Done():
+				_ = cmd.Wait()
+				return
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			fmt.Fprintf(stderr, "docker compose watch exited with error: %v\n", err)
+		}
+	}()
+
+	return events, nil
+}
+
+// parseWatchLine turns a single line of `docker compose watch` output into
+// a typed WatchEvent. The real CLI's output isn't newline-delimited JSON,
+// so this is a best-effort classification of its human-readable log lines;
+// ok is false for banner/blank/unrecognized lines, which callers should
+// drop rather than report as a synthetic file-changed event.
+func parseWatchLine(line string) (WatchEvent, bool) {
+	evt := WatchEvent{}
+	switch {
+	case strings.Contains(line, "Rebuilding"):
+		evt.Type = WatchEventTypeRebuildTriggered
+	case strings.Contains(line, "Restarting"):
+		evt.Type = WatchEventTypeServiceRestarted
+	case strings.Contains(line, "Syncing"):
+		evt.Type = WatchEventTypeSyncStarted
+	case strings.Contains(line, "Synced"):
+		evt.Type = WatchEventTypeSyncFinished
+	default:
+		return WatchEvent{}, false
+	}
+	return evt, true
+}
+
+// Config renders the effective compose YAML for proj. The rendered output
+// reflects proj.Profiles (only the selected services are included) and
+// proj.Compatibility (v3 `deploy` keys translated to v2 equivalents).
+func (c *DCClient) Config(ctx context.Context, proj model.DockerComposeProject) (string, error) {
+	configPaths, err := c.resolveConfigPaths(ctx, proj)
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{"compose"}, configFileArgs(configPaths)...)
+	for _, prof := range proj.Profiles {
+		args = append(args, "--profile", prof)
+	}
+	if proj.Compatibility {
+		args = append(args, "--compatibility")
+	}
+	args = append(args, "config")
+
+	var stdout bytes.Buffer
+	if err := c.run(ctx, args, &stdout, io.Discard); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// Project resolves m into a loaded compose project, pulling it from an OCI
+// registry first if m.OCIRef is set, then applying m.Compatibility and
+// m.Profiles.
+func (c *DCClient) Project(ctx context.Context, m model.DockerComposeProject) (*types.Project, error) {
+	configPaths, err := c.resolveConfigPaths(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+
+	var configFiles []types.ConfigFile
+	for _, p := range configPaths {
+		contents, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		configFiles = append(configFiles, types.ConfigFile{Filename: p, Content: contents})
+	}
+
+	compatibilityOpt := func(opts *loader.Options) {
+		opts.Compatibility = m.Compatibility
+	}
+
+	p, err := loader.Load(types.ConfigDetails{ConfigFiles: configFiles}, dcLoaderOption, compatibilityOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Profiles) > 0 {
+		p, err = p.ApplyProfiles(m.Profiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// resolveConfigPaths returns the local compose YAML paths for proj,
+// pulling it from its OCI registry first if proj.OCIRef is set. Every
+// method that shells out to `docker compose` against a project (Project,
+// Down, Watch, Config) goes through this so OCI-hosted projects are
+// resolved consistently instead of only in Project.
+func (c *DCClient) resolveConfigPaths(ctx context.Context, proj model.DockerComposeProject) ([]string, error) {
+	if proj.OCIRef == "" {
+		return proj.ConfigPaths, nil
+	}
+	configPaths, err := c.resolveOCIProject(ctx, proj.OCIRef)
+	if err != nil {
+		return nil, fmt.Errorf("pulling compose project %s: %v", proj.OCIRef, err)
+	}
+	return configPaths, nil
+}
+
+// resolveOCIProject pulls the compose project hosted at ref from its OCI
+// registry, caching the manifest and the compose YAML it references on
+// disk, and returns the local paths to the materialized compose files.
+// Registry pulls are retried with exponential backoff, since registries are
+// a common source of transient failures.
+func (c *DCClient) resolveOCIProject(ctx context.Context, ref string) ([]string, error) {
+	dest := filepath.Join(c.ociCacheDir, ociCacheKey(ref))
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+
+	var composeFile string
+	err := retryWithBackoff(ctx, 5, 500*time.Millisecond, func() error {
+		if err := c.pullOCIArtifact(ctx, ref, dest); err != nil {
+			return err
+		}
+		cf := filepath.Join(dest, "docker-compose.yml")
+		if _, err := os.Stat(cf); err != nil {
+			return fmt.Errorf("pulled OCI artifact %s did not contain a docker-compose.yml: %v", ref, err)
+		}
+		composeFile = cf
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{composeFile}, nil
+}
+
+// retryWithBackoff calls fn up to maxAttempts times, doubling backoff after
+// each failed attempt (starting at initialBackoff), and returns nil as soon
+// as fn succeeds. It gives up early if ctx is cancelled while waiting
+// between attempts.
+func retryWithBackoff(ctx context.Context, maxAttempts int, initialBackoff time.Duration, fn func() error) error {
+	backoff := initialBackoff
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err := fn(); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %v", maxAttempts, lastErr)
+}
+
+// pullOCIArtifact pulls the image backing ref and extracts its filesystem
+// (the cached manifest + compose YAML) into destDir.
+func (c *DCClient) pullOCIArtifact(ctx context.Context, ref string, destDir string) error {
+	image := strings.TrimPrefix(ref, "oci://")
+
+	if err := c.run(ctx, []string{"pull", image}, io.Discard, io.Discard); err != nil {
+		return fmt.Errorf("docker pull: %v", err)
+	}
+
+	var createOut bytes.Buffer
+	if err := c.run(ctx, []string{"create", image}, &createOut, io.Discard); err != nil {
+		return fmt.Errorf("docker create: %v", err)
+	}
+	containerID := strings.TrimSpace(createOut.String())
+	defer func() {
+		_ = c.run(ctx, []string{"rm", containerID}, io.Discard, io.Discard)
+	}()
+
+	if err := c.run(ctx, []string{"cp", containerID + ":/.", destDir}, io.Discard, io.Discard); err != nil {
+		return fmt.Errorf("docker cp: %v", err)
+	}
+	return nil
+}
+
+func (c *DCClient) ContainerID(ctx context.Context, spec model.DockerComposeUpSpec) (container.ID, error) {
+	var stdout bytes.Buffer
+	if err := c.run(ctx, []string{"compose", "ps", "-q", spec.Service}, &stdout, io.Discard); err != nil {
+		return "", err
+	}
+	return container.ID(strings.TrimSpace(stdout.String())), nil
+}
+
+func (c *DCClient) Version(ctx context.Context) (string, string, ComposeCapabilities, error) {
+	var stdout bytes.Buffer
+	if err := c.run(ctx, []string{"compose", "version", "--short"}, &stdout, io.Discard); err != nil {
+		return "", "", ComposeCapabilities{}, err
+	}
+	version := strings.TrimSpace(stdout.String())
+	return version, "docker-compose", capabilitiesForVersion(version), nil
+}
+
+// ComposeCapabilities describes the feature set of an installed `docker
+// compose`, derived from its version, so that callers can gate features
+// like Watch or OCI project loading without doing their own semver
+// comparisons against the raw version string.
+type ComposeCapabilities struct {
+	Watch            bool
+	Wait             bool
+	Develop          bool
+	OCIRemoteLoading bool
+	ConfigFormatJSON bool
+}
+
+// composeVersionRegexp matches the "vMAJOR.MINOR.PATCH" prefix out of a
+// `docker compose version --short` output like "v2.24.1" or "2.24.1".
+var composeVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// capabilitiesForVersion derives a ComposeCapabilities from a raw compose
+// version string. An unparseable version is treated conservatively, as if
+// no optional feature were supported.
+func capabilitiesForVersion(raw string) ComposeCapabilities {
+	m := composeVersionRegexp.FindStringSubmatch(raw)
+	if m == nil {
+		return ComposeCapabilities{}
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+
+	atLeast := func(wantMajor, wantMinor int) bool {
+		return major > wantMajor || (major == wantMajor && minor >= wantMinor)
+	}
+
+	return ComposeCapabilities{
+		Wait:             atLeast(2, 17),
+		ConfigFormatJSON: atLeast(2, 21),
+		Watch:            atLeast(2, 22),
+		Develop:          atLeast(2, 22),
+		OCIRemoteLoading: atLeast(2, 24),
+	}
+}
+
+func (c *DCClient) run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func configFileArgs(configPaths []string) []string {
+	var args []string
+	for _, p := range configPaths {
+		args = append(args, "-f", p)
+	}
+	return args
+}
+
+// ociCacheKey turns an OCI ref into a filesystem-safe cache directory name.
+func ociCacheKey(ref string) string {
+	r := strings.NewReplacer("/", "_", ":", "_", "@", "_")
+	return r.Replace(strings.TrimPrefix(ref, "oci://"))
+}
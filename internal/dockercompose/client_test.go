@@ -0,0 +1,129 @@
+package dockercompose
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilitiesForVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		version string
+		want    ComposeCapabilities
+	}{
+		{"old", "v2.10.0", ComposeCapabilities{}},
+		{"wait only", "v2.17.0", ComposeCapabilities{Wait: true}},
+		{"config format json", "v2.21.0", ComposeCapabilities{Wait: true, ConfigFormatJSON: true}},
+		{"watch and develop", "v2.22.0", ComposeCapabilities{
+			Wait: true, ConfigFormatJSON: true, Watch: true, Develop: true,
+		}},
+		{"everything", "v2.24.3", ComposeCapabilities{
+			Wait: true, ConfigFormatJSON: true, Watch: true, Develop: true, OCIRemoteLoading: true,
+		}},
+		{"no leading v", "2.24.3", ComposeCapabilities{
+			Wait: true, ConfigFormatJSON: true, Watch: true, Develop: true, OCIRemoteLoading: true,
+		}},
+		{"unparseable", "not-a-version", ComposeCapabilities{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, capabilitiesForVersion(tc.version))
+		})
+	}
+}
+
+func TestBuildxProgressMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		want string
+	}{
+		{"", "auto"},
+		{"auto", "auto"},
+		{"plain", "plain"},
+		{"tty", "tty"},
+		{"quiet", "quiet"},
+		{"rawjson", "plain"},
+		{"bogus", "auto"},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			assert.Equal(t, tc.want, buildxProgressMode(tc.mode))
+		})
+	}
+}
+
+func TestParseWatchLine(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		line    string
+		wantEvt WatchEvent
+		wantOK  bool
+	}{
+		{"rebuilding", "web  Rebuilding", WatchEvent{Type: WatchEventTypeRebuildTriggered}, true},
+		{"restarting", "web  Restarting", WatchEvent{Type: WatchEventTypeServiceRestarted}, true},
+		{"syncing", "web  Syncing app.py", WatchEvent{Type: WatchEventTypeSyncStarted}, true},
+		{"synced", "web  Synced app.py", WatchEvent{Type: WatchEventTypeSyncFinished}, true},
+		{"banner", "Watch enabled", WatchEvent{}, false},
+		{"blank", "", WatchEvent{}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evt, ok := parseWatchLine(tc.line)
+			assert.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				assert.Equal(t, tc.wantEvt, evt)
+			}
+		})
+	}
+}
+
+func TestOCICacheKey(t *testing.T) {
+	for _, tc := range []struct {
+		ref  string
+		want string
+	}{
+		{"oci://registry.example.com/myapp:tag", "registry.example.com_myapp_tag"},
+		{"registry.example.com/myapp@sha256:abc", "registry.example.com_myapp_sha256_abc"},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			assert.Equal(t, tc.want, ociCacheKey(tc.ref))
+		})
+	}
+}
+
+func TestRetryWithBackoffSucceedsAfterRetries(t *testing.T) {
+	attempts := 0
+	err := retryWithBackoff(context.Background(), 5, time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("not yet")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		attempts++
+		return fmt.Errorf("always fails")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryWithBackoffStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := retryWithBackoff(ctx, 5, 10*time.Millisecond, func() error {
+		attempts++
+		return fmt.Errorf("always fails")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
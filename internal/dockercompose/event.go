@@ -0,0 +1,34 @@
+package dockercompose
+
+// Event is a single `docker compose events --format json` entry, as
+// streamed back by StreamEvents.
+type Event struct {
+	Type    string `json:"type"`
+	Service string `json:"service"`
+	Action  string `json:"action"`
+}
+
+// WatchEventType enumerates the kinds of events `docker compose watch`
+// emits while driving a service's x-develop.watch rules.
+type WatchEventType string
+
+const (
+	WatchEventTypeFileChanged      WatchEventType = "file_changed"
+	WatchEventTypeSyncStarted      WatchEventType = "sync_started"
+	WatchEventTypeSyncFinished     WatchEventType = "sync_finished"
+	WatchEventTypeRebuildTriggered WatchEventType = "rebuild_triggered"
+	WatchEventTypeServiceRestarted WatchEventType = "service_restarted"
+)
+
+// WatchEvent is a single structured event parsed from `docker compose
+// watch`'s output, exposed over the channel Watch returns so Tilt's
+// controllers can display per-file-sync status instead of parsing raw log
+// lines.
+type WatchEvent struct {
+	Type    WatchEventType `json:"type"`
+	Service string         `json:"service"`
+	// Path is the file that changed, for FileChanged/SyncStarted/
+	// SyncFinished events. The real client's Watch does not fill in Path
+	// or Service: parseWatchLine sets only Type.
+	Path string `json:"path,omitempty"`
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/compose-spec/compose-go/loader"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/compose-spec/compose-go/types"
 
@@ -23,15 +24,30 @@ import (
 	"github.com/tilt-dev/tilt/pkg/model"
 )
 
+// dcProjectOptions and dcLoaderOption are the baseline compose-go options
+// Tilt applies. dcLoaderOption is shared between the real and fake
+// clients' Project; dcProjectOptions is only used by the fake's Project.
+var dcProjectOptions = []compose.ProjectOptionsFn{}
+
+func dcLoaderOption(opts *loader.Options) {
+	opts.SkipValidation = true
+}
+
 type FakeDCClient struct {
 	t   *testing.T
 	ctx context.Context
 
-	RunLogOutput      map[string]<-chan string
-	ContainerIdOutput container.ID
-	eventJson         chan string
-	ConfigOutput      string
-	VersionOutput     string
+	RunLogOutput       map[string]<-chan string
+	ContainerIdOutput  container.ID
+	eventJson          chan string
+	ConfigOutput       string
+	VersionOutput      string
+	// CapabilitiesOutput overrides the capabilities Version() reports. A
+	// nil value (the default) derives capabilities from VersionOutput, the
+	// same way the real client does; a non-nil value — including an
+	// explicit &ComposeCapabilities{} for "nothing supported" — is
+	// returned verbatim.
+	CapabilitiesOutput *ComposeCapabilities
 
 	UpCalls   []UpCall
 	DownCalls []DownCall
@@ -39,6 +55,16 @@ type FakeDCClient struct {
 	DownError error
 	RmError   error
 	WorkDir   string
+
+	// OCIPullCalls records the OCI refs that Project() resolved, in the
+	// order they were requested, so tests can assert on pull behavior.
+	OCIPullCalls []string
+	// OCIPullError is returned from Project() the next time it resolves
+	// an OCI-backed project, then cleared.
+	OCIPullError error
+
+	WatchCalls []WatchCall
+	watchJson  chan string
 }
 
 var _ DockerComposeClient = &FakeDCClient{}
@@ -47,6 +73,9 @@ var _ DockerComposeClient = &FakeDCClient{}
 type UpCall struct {
 	Spec        model.DockerComposeUpSpec
 	ShouldBuild bool
+	// BuildProgress is the progress printer mode (e.g. "auto", "plain",
+	// "tty", "quiet", "rawjson") that was requested for this Up call.
+	BuildProgress string
 }
 
 // Represents a single call to Down
@@ -58,18 +87,25 @@ type RmCall struct {
 	Specs []model.DockerComposeUpSpec
 }
 
+// Represents a single call to Watch
+type WatchCall struct {
+	Proj  model.DockerComposeProject
+	Specs []model.DockerComposeUpSpec
+}
+
 func NewFakeDockerComposeClient(t *testing.T, ctx context.Context) *FakeDCClient {
 	return &FakeDCClient{
 		t:            t,
 		ctx:          ctx,
 		eventJson:    make(chan string, 100),
+		watchJson:    make(chan string, 100),
 		RunLogOutput: make(map[string]<-chan string),
 	}
 }
 
 func (c *FakeDCClient) Up(ctx context.Context, spec model.DockerComposeUpSpec,
 	shouldBuild bool, stdout, stderr io.Writer) error {
-	c.UpCalls = append(c.UpCalls, UpCall{spec, shouldBuild})
+	c.UpCalls = append(c.UpCalls, UpCall{spec, shouldBuild, spec.BuildProgress})
 	return nil
 }
 
@@ -160,15 +196,85 @@ func (c *FakeDCClient) SendEvent(evt Event) error {
 	return nil
 }
 
-func (c *FakeDCClient) Config(_ context.Context, _ []string) (string, error) {
-	return c.ConfigOutput, nil
+// Watch fakes out `docker compose watch`: it just records the call and
+// streams back whatever events tests inject via SendWatchEvent.
+func (c *FakeDCClient) Watch(ctx context.Context, proj model.DockerComposeProject,
+	specs []model.DockerComposeUpSpec, stdout, stderr io.Writer) (<-chan string, error) {
+	c.WatchCalls = append(c.WatchCalls, WatchCall{proj, specs})
+
+	events := make(chan string, 10)
+	go func() {
+		for {
+			select {
+			case event := <-c.watchJson:
+				select {
+				case events <- event: // send event to channel (unless it's full)
+				default:
+					panic(fmt.Sprintf("no room on watch events channel to send event: '%s'. Something "+
+						"is wrong (or you need to increase the buffer).", event))
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// SendWatchEvent injects a synthetic `compose watch` event (file changed,
+// sync started/finished, rebuild triggered, service restarted) for tests
+// to assert against the channel returned by Watch.
+func (c *FakeDCClient) SendWatchEvent(evt WatchEvent) error {
+	j, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	c.watchJson <- string(j)
+	return nil
+}
+
+// Config returns c.ConfigOutput as-is, unless proj selects a profile
+// subset or enables compatibility mode, in which case it loads the
+// project, applies those options, and re-renders the result — mirroring
+// the real client's behavior of reflecting them in the rendered YAML.
+func (c *FakeDCClient) Config(ctx context.Context, proj model.DockerComposeProject) (string, error) {
+	if len(proj.Profiles) == 0 && !proj.Compatibility {
+		return c.ConfigOutput, nil
+	}
+	p, err := c.Project(ctx, proj)
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
 }
 
 func (c *FakeDCClient) Project(_ context.Context, m model.DockerComposeProject) (*types.Project, error) {
+	if m.OCIRef != "" {
+		c.OCIPullCalls = append(c.OCIPullCalls, m.OCIRef)
+		if c.OCIPullError != nil {
+			err := c.OCIPullError
+			c.OCIPullError = nil
+			return nil, err
+		}
+		// in the real client, this is where the OCI artifact would be
+		// pulled and its manifest + referenced compose YAML materialized
+		// on disk; the fake just falls through to ConfigOutput below as
+		// if that resolution had already happened.
+	}
+
 	// this is a dummy ProjectOptions that lets us use compose's logic to apply options
 	// for consistency, but we have to then pull the data out ourselves since we're calling
 	// loader.Load ourselves
-	opts, err := compose.NewProjectOptions(nil, dcProjectOptions...)
+	projectOptions := append([]compose.ProjectOptionsFn{}, dcProjectOptions...)
+	if len(m.Profiles) > 0 {
+		projectOptions = append(projectOptions, compose.WithProfiles(m.Profiles))
+	}
+	opts, err := compose.NewProjectOptions(nil, projectOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +293,9 @@ func (c *FakeDCClient) Project(_ context.Context, m model.DockerComposeProject)
 			opt.Name = "fakedc"
 		}
 	}
+	compatibilityOpt := func(opt *loader.Options) {
+		opt.Compatibility = m.Compatibility
+	}
 
 	p, err := loader.Load(types.ConfigDetails{
 		WorkingDir: workDir,
@@ -196,18 +305,36 @@ func (c *FakeDCClient) Project(_ context.Context, m model.DockerComposeProject)
 			},
 		},
 		Environment: opts.Environment,
-	}, dcLoaderOption, projectNameOpt)
-	return p, err
+	}, dcLoaderOption, projectNameOpt, compatibilityOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Profiles) > 0 {
+		p, err = p.ApplyProfiles(m.Profiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
 }
 
 func (c *FakeDCClient) ContainerID(ctx context.Context, spec model.DockerComposeUpSpec) (container.ID, error) {
 	return c.ContainerIdOutput, nil
 }
 
-func (c *FakeDCClient) Version(_ context.Context) (string, string, error) {
-	if c.VersionOutput != "" {
-		return c.VersionOutput, "tilt-fake", nil
+func (c *FakeDCClient) Version(_ context.Context) (string, string, ComposeCapabilities, error) {
+	version := c.VersionOutput
+	if version == "" {
+		// default to a "known good" version that won't produce warnings
+		version = "v1.29.2"
 	}
-	// default to a "known good" version that won't produce warnings
-	return "v1.29.2", "tilt-fake", nil
+
+	caps := capabilitiesForVersion(version)
+	if c.CapabilitiesOutput != nil {
+		caps = *c.CapabilitiesOutput
+	}
+
+	return version, "tilt-fake", caps, nil
 }
@@ -0,0 +1,36 @@
+package model
+
+// DockerComposeUpSpec describes a single service that Tilt brings up via
+// `docker compose up`.
+type DockerComposeUpSpec struct {
+	Service string
+
+	// BuildProgress controls how BuildKit progress from `docker compose up
+	// --build` is rendered: "auto", "plain", "tty", "quiet", or "rawjson".
+	// Empty means "auto".
+	BuildProgress string
+}
+
+// DockerComposeProject identifies the compose project (the set of YAML
+// files, plus any modifiers) that a DockerComposeClient operates against.
+type DockerComposeProject struct {
+	// ConfigPaths are the local compose YAML files that make up this
+	// project. Empty if the project is loaded from an OCI artifact.
+	ConfigPaths []string
+
+	// OCIRef is an OCI artifact reference (e.g.
+	// "oci://registry.example.com/myapp:tag") that the compose project
+	// should be resolved from instead of ConfigPaths. When set, the
+	// client pulls the artifact, caches its manifest and referenced
+	// compose YAML on disk, and loads the project from there.
+	OCIRef string
+
+	// Profiles restricts the project to the given `compose --profile`
+	// selection, so a Tiltfile can bring up only a subset of services.
+	Profiles []string
+
+	// Compatibility enables compose-go's compatibility mode, which
+	// translates v3 `deploy` keys to their v2 equivalents so legacy
+	// stacks load without manual edits.
+	Compatibility bool
+}